@@ -0,0 +1,263 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cassandra
+
+import (
+	"crypto/tls"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gocql/gocql"
+
+	"github.com/uber/cadence/common/config"
+)
+
+const (
+	// DefaultTimeout is the default timeout, in seconds, for queries issued by this tool
+	DefaultTimeout = 30
+	// DefaultConnectTimeout is the default timeout, in seconds, for initial cluster connection
+	DefaultConnectTimeout = 30
+	// DefaultCassandraPort is the default port used to connect to Cassandra
+	DefaultCassandraPort = 9042
+	// SystemKeyspace is the keyspace that hosts Cassandra's own system tables;
+	// keyspaceExists/doCreateKeyspace connect to it to manage other keyspaces.
+	SystemKeyspace = "system"
+)
+
+const (
+	createSchemaVersionTableCQL = `CREATE TABLE IF NOT EXISTS schema_version (` +
+		`keyspace_name text, ` +
+		`creation_time timestamp, ` +
+		`curr_version text, ` +
+		`min_compatible_version text, ` +
+		`PRIMARY KEY (keyspace_name))`
+
+	writeSchemaVersionCQL = `INSERT INTO schema_version ` +
+		`(keyspace_name, creation_time, curr_version, min_compatible_version) VALUES (?, ?, ?, ?)`
+
+	createSchemaUpdateHistoryTableCQL = `CREATE TABLE IF NOT EXISTS schema_update_history (` +
+		`year int, ` +
+		`month int, ` +
+		`update_time timestamp, ` +
+		`old_version text, ` +
+		`new_version text, ` +
+		`manifest_md5 text, ` +
+		`description text, ` +
+		`PRIMARY KEY ((year, month), update_time))`
+
+	writeSchemaUpdateHistoryCQL = `INSERT INTO schema_update_history ` +
+		`(year, month, update_time, old_version, new_version, manifest_md5, description) VALUES (?, ?, ?, ?, ?, ?, ?)`
+)
+
+// CQLClientConfig holds the connection and behavior parameters NewCQLClient
+// needs to dial a keyspace: hosts/port/credentials/TLS, plus the replication
+// factor used when this config is later reused to create a keyspace.
+type CQLClientConfig struct {
+	Hosts                 string
+	Port                  int
+	User                  string
+	Password              string
+	Keyspace              string
+	AllowedAuthenticators []string
+	Timeout               int
+	ConnectTimeout        int
+	ProtoVersion          int
+	NumReplicas           int
+	TLS                   *config.TLS
+}
+
+// CQLClient is a CQL session bound to a single keyspace. It implements
+// tools/common/schema.DB, so the setup/update CLI handlers and the
+// golang-migrate database.Driver adapter in migrate_driver.go can drive
+// schema changes directly over the one connection it holds.
+type CQLClient struct {
+	session     *gocql.Session
+	keyspace    string
+	numReplicas int
+}
+
+// NewCQLClient creates a CQLClient connected to cfg.Keyspace over cfg.Hosts.
+func NewCQLClient(cfg *CQLClientConfig) (*CQLClient, error) {
+	cluster := gocql.NewCluster(strings.Split(cfg.Hosts, ",")...)
+	cluster.ProtoVersion = cfg.ProtoVersion
+	cluster.Port = cfg.Port
+	cluster.Keyspace = cfg.Keyspace
+	cluster.Timeout = time.Duration(cfg.Timeout) * time.Second
+	cluster.ConnectTimeout = time.Duration(cfg.ConnectTimeout) * time.Second
+	cluster.Consistency = gocql.All
+
+	if cfg.User != "" {
+		cluster.Authenticator = gocql.PasswordAuthenticator{
+			Username: cfg.User,
+			Password: cfg.Password,
+		}
+	}
+
+	if cfg.TLS != nil && cfg.TLS.Enabled {
+		cluster.SslOpts = &gocql.SslOptions{
+			CertPath:               cfg.TLS.CertFile,
+			KeyPath:                cfg.TLS.KeyFile,
+			CaPath:                 cfg.TLS.CaFile,
+			EnableHostVerification: cfg.TLS.EnableHostVerification,
+			Config: &tls.Config{
+				ServerName: cfg.TLS.ServerName,
+			},
+		}
+	}
+
+	session, err := cluster.CreateSession()
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to cassandra cluster: %v", err)
+	}
+
+	return &CQLClient{
+		session:     session,
+		keyspace:    cfg.Keyspace,
+		numReplicas: cfg.NumReplicas,
+	}, nil
+}
+
+// Session returns the underlying *gocql.Session, for callers (e.g.
+// RollingUpgrader) that need to run queries this client doesn't expose directly.
+func (c *CQLClient) Session() *gocql.Session {
+	return c.session
+}
+
+// Close releases the underlying CQL session.
+func (c *CQLClient) Close() {
+	if c.session != nil {
+		c.session.Close()
+	}
+}
+
+// Exec runs a single CQL statement with no bound values.
+func (c *CQLClient) Exec(stmt string) error {
+	return c.session.Query(stmt).Exec()
+}
+
+// ListTables returns the names of every table in the client's keyspace.
+func (c *CQLClient) ListTables() ([]string, error) {
+	// Iter.Scan closes the iterator after one row, so read the raw gocql
+	// iterator here and close it once every row has been scanned.
+	iter := c.session.Query(
+		`SELECT table_name FROM system_schema.tables WHERE keyspace_name = ?`, c.keyspace,
+	).Iter()
+
+	var tables []string
+	var table string
+	for iter.Scan(&table) {
+		tables = append(tables, table)
+	}
+	if err := iter.Close(); err != nil {
+		return nil, fmt.Errorf("unable to list tables in keyspace %q: %w", c.keyspace, err)
+	}
+	return tables, nil
+}
+
+// ReadMigrationVersion reads the single row golang-migrate keeps in
+// migrationsTable, returning ok=false if the table is empty (no migration has
+// ever run) rather than treating that as an error.
+func (c *CQLClient) ReadMigrationVersion(migrationsTable string) (version int, dirty bool, ok bool, err error) {
+	iter := NewIter(c.session.Query(
+		fmt.Sprintf(`SELECT version, dirty FROM %s LIMIT 1`, migrationsTable),
+	).Iter())
+
+	ok, err = iter.Scan(&version, &dirty)
+	if err != nil {
+		return 0, false, false, fmt.Errorf("unable to read %s: %w", migrationsTable, err)
+	}
+	return version, dirty, ok, nil
+}
+
+// KeyspaceExists reports whether name is present in system_schema.keyspaces.
+func (c *CQLClient) KeyspaceExists(name string) (bool, error) {
+	iter := NewIter(c.session.Query(
+		`SELECT keyspace_name FROM system_schema.keyspaces WHERE keyspace_name = ?`, name,
+	).Iter())
+
+	var found string
+	ok, err := iter.Scan(&found)
+	if err != nil {
+		return false, fmt.Errorf("unable to check existence of keyspace %q: %w", name, err)
+	}
+	return ok, nil
+}
+
+// CreateKeyspace creates name with a SimpleStrategy replication factor of
+// c.numReplicas. Use CreateNTSKeyspace instead for datacenter-aware clusters.
+func (c *CQLClient) CreateKeyspace(name string) error {
+	return c.Exec(fmt.Sprintf(
+		`CREATE KEYSPACE IF NOT EXISTS %s WITH replication = { 'class' : 'SimpleStrategy', 'replication_factor' : %d }`,
+		name, c.numReplicas,
+	))
+}
+
+// CreateNTSKeyspace creates name with a NetworkTopologyStrategy scoped to
+// datacenter, replicating c.numReplicas times within it.
+func (c *CQLClient) CreateNTSKeyspace(name string, datacenter string) error {
+	return c.Exec(fmt.Sprintf(
+		`CREATE KEYSPACE IF NOT EXISTS %s WITH replication = { 'class' : 'NetworkTopologyStrategy', '%s' : %d }`,
+		name, datacenter, c.numReplicas,
+	))
+}
+
+// CreateSchemaVersionTable creates the schema_version table that setup writes
+// its initial bookkeeping row to, if it doesn't already exist.
+func (c *CQLClient) CreateSchemaVersionTable() error {
+	return c.Exec(createSchemaVersionTableCQL)
+}
+
+// UpdateSchemaVersion records newVersion, and the oldest version still
+// compatible with it, as the client's keyspace's current schema version.
+func (c *CQLClient) UpdateSchemaVersion(newVersion string, minCompatibleVersion string) error {
+	return c.session.Query(
+		writeSchemaVersionCQL, c.keyspace, time.Now(), newVersion, minCompatibleVersion,
+	).Exec()
+}
+
+// WriteSchemaUpdateLog appends an entry to schema_update_history recording a
+// transition from oldVersion to newVersion, creating the table first if this
+// is the first update ever recorded.
+func (c *CQLClient) WriteSchemaUpdateLog(oldVersion string, newVersion string, manifestMD5 string, desc string) error {
+	if err := c.Exec(createSchemaUpdateHistoryTableCQL); err != nil {
+		return err
+	}
+	now := time.Now()
+	return c.session.Query(
+		writeSchemaUpdateHistoryCQL, now.Year(), int(now.Month()), now, oldVersion, newVersion, manifestMD5, desc,
+	).Exec()
+}
+
+// DropAllTables drops every table in the client's keyspace, backing the
+// --overwrite flow setup-schema gates behind confirmDestructive.
+func (c *CQLClient) DropAllTables() error {
+	tables, err := c.ListTables()
+	if err != nil {
+		return err
+	}
+	for _, t := range tables {
+		if err := c.Exec(fmt.Sprintf(`DROP TABLE IF EXISTS %s`, t)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cassandra
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/urfave/cli"
+
+	"github.com/uber/cadence/tools/nosql"
+)
+
+const (
+	// CLIFlagForce bypasses the interactive confirmation prompt for destructive schema/keyspace operations
+	CLIFlagForce = "force"
+	// CLIFlagYes is an alias for CLIFlagForce
+	CLIFlagYes = "yes"
+)
+
+// GlobalFlags returns the --force/--yes flag definitions confirmDestructive
+// reads via cli.GlobalBool. The owning cli.App (e.g. cmd/tools/cassandra)
+// must include these in its top-level Flags, the same way it registers
+// schema.CLIOpt*'s connection flags, or the flags are undefined and
+// non-interactive setup-schema --overwrite/update-schema runs fail closed
+// with ErrConfirmationMissing instead of honoring --force/--yes.
+func GlobalFlags() []cli.Flag {
+	return []cli.Flag{
+		cli.BoolFlag{
+			Name:  CLIFlagForce,
+			Usage: "bypass the interactive confirmation prompt for destructive schema/keyspace operations",
+		},
+		cli.BoolFlag{
+			Name:  CLIFlagYes,
+			Usage: "alias for --force",
+		},
+	}
+}
+
+// ErrConfirmationMissing is returned when a command would drop tables,
+// overwrite an existing keyspace, or downgrade the schema version, but
+// neither --force/--yes nor an interactive "yes" confirmation was given.
+var ErrConfirmationMissing = errors.New("refusing destructive operation without confirmation (pass --force/--yes or confirm interactively)")
+
+// confirmDestructive guards a destructive action. It succeeds immediately if
+// --force/--yes was passed; otherwise, on a TTY, it prompts the operator to
+// type "yes". It returns ErrConfirmationMissing if neither applies, so CI and
+// other non-interactive runs fail closed instead of silently proceeding.
+func confirmDestructive(cli *cli.Context, description string) error {
+	if cli.GlobalBool(CLIFlagForce) || cli.GlobalBool(CLIFlagYes) {
+		return nil
+	}
+	if !isTerminal(os.Stdin) {
+		return ErrConfirmationMissing
+	}
+
+	fmt.Printf("%s\nThis is a destructive operation. Type \"yes\" to continue: ", description)
+	line, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	if strings.TrimSpace(line) != "yes" {
+		return ErrConfirmationMissing
+	}
+	return nil
+}
+
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return (fi.Mode() & os.ModeCharDevice) != 0
+}
+
+// schemaVersionReader is the narrow interface checkNotDowngrade needs. It's
+// satisfied by *SchemaVersionReader and easy to fake in tests.
+type schemaVersionReader interface {
+	ReadSchemaVersion(keyspace string) (string, error)
+}
+
+// checkNotDowngrade compares the schema version currently recorded for
+// keyspace against requestedVersion and returns an error describing the
+// downgrade rather than allowing it to proceed silently. A missing current
+// version (first-time setup, ErrGetSchemaVersion) is not treated as a
+// downgrade, but any other error -- e.g. a transport failure -- is returned
+// rather than silently treated as "nothing to downgrade from", since that
+// would let a flaky read defeat this check entirely.
+func checkNotDowngrade(reader schemaVersionReader, keyspace string, requestedVersion string) error {
+	if requestedVersion == "" {
+		return nil
+	}
+	currentVersion, err := reader.ReadSchemaVersion(keyspace)
+	if errors.Is(err, ErrGetSchemaVersion) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("unable to determine current schema version for keyspace %q: %w", keyspace, err)
+	}
+	if nosql.VersionLess(requestedVersion, currentVersion) {
+		return fmt.Errorf(
+			"refusing to downgrade keyspace %q schema from %v to %v: %w",
+			keyspace, currentVersion, requestedVersion, ErrConfirmationMissing,
+		)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cassandra
+
+import (
+	"errors"
+	"testing"
+)
+
+type fakeSchemaVersionReader struct {
+	version string
+	err     error
+}
+
+func (f *fakeSchemaVersionReader) ReadSchemaVersion(keyspace string) (string, error) {
+	return f.version, f.err
+}
+
+func TestCheckNotDowngrade(t *testing.T) {
+	tests := []struct {
+		name             string
+		currentVersion   string
+		currentErr       error
+		requestedVersion string
+		wantErr          bool
+	}{
+		{name: "no requested version is always allowed", requestedVersion: "", wantErr: false},
+		{name: "upgrade is allowed", currentVersion: "0.32", requestedVersion: "0.33", wantErr: false},
+		{name: "same version is allowed", currentVersion: "0.32", requestedVersion: "0.32", wantErr: false},
+		{name: "downgrade is refused", currentVersion: "0.32", requestedVersion: "0.9", wantErr: true},
+		{name: "first-time setup (no rows yet) is allowed", currentErr: ErrGetSchemaVersion, requestedVersion: "0.32", wantErr: false},
+		{name: "transport error fails closed, not silently allowed", currentErr: errors.New("connection reset"), requestedVersion: "0.32", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := &fakeSchemaVersionReader{version: tt.currentVersion, err: tt.currentErr}
+			err := checkNotDowngrade(reader, "ks", tt.requestedVersion)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkNotDowngrade() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckNotDowngradeTransportErrorNotConfirmationMissing(t *testing.T) {
+	reader := &fakeSchemaVersionReader{err: errors.New("connection reset")}
+	err := checkNotDowngrade(reader, "ks", "0.32")
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if errors.Is(err, ErrConfirmationMissing) {
+		t.Errorf("transport error should not be reported as ErrConfirmationMissing: %v", err)
+	}
+}
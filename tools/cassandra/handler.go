@@ -21,14 +21,17 @@
 package cassandra
 
 import (
+	"errors"
 	"fmt"
 	"log"
 
 	"github.com/urfave/cli"
+	"golang.org/x/sync/errgroup"
 
 	"github.com/uber/cadence/common/config"
 	"github.com/uber/cadence/schema/cassandra"
 	"github.com/uber/cadence/tools/common/schema"
+	"github.com/uber/cadence/tools/nosql"
 )
 
 const defaultNumReplicas = 1
@@ -44,22 +47,32 @@ type SetupSchemaConfig struct {
 // In most cases, the versions should match. However if after a schema upgrade there is a code
 // rollback, the code version (expected version) would fall lower than the actual version in
 // cassandra.
+// checkVisibility controls whether the visibility keyspace is probed at all: operators running
+// advanced visibility (e.g. Elasticsearch/OpenSearch) don't use the Cassandra visibility
+// keyspace, so the check is meaningless for them and should be skipped.
 func VerifyCompatibleVersion(
 	cfg config.Persistence,
+	checkVisibility bool,
 ) error {
-	if ds, ok := cfg.DataStores[cfg.DefaultStore]; ok {
-		if err := verifyCompatibleVersion(ds, cassandra.Version); err != nil {
-			return err
-		}
-	}
+	var g errgroup.Group
 
-	if ds, ok := cfg.DataStores[cfg.VisibilityStore]; ok {
-		if err := verifyCompatibleVersion(ds, cassandra.VisibilityVersion); err != nil {
-			return err
+	g.Go(func() error {
+		if ds, ok := cfg.DataStores[cfg.DefaultStore]; ok {
+			return verifyCompatibleVersion(ds, cassandra.Version)
 		}
+		return nil
+	})
+
+	if checkVisibility {
+		g.Go(func() error {
+			if ds, ok := cfg.DataStores[cfg.VisibilityStore]; ok {
+				return verifyCompatibleVersion(ds, cassandra.VisibilityVersion)
+			}
+			return nil
+		})
 	}
 
-	return nil
+	return g.Wait()
 }
 
 func verifyCompatibleVersion(
@@ -71,11 +84,20 @@ func verifyCompatibleVersion(
 		return nil
 	}
 
-	// Use hardcoded instead of constant because of cycle dependency issue.
-	// However, this file will be refactor to support NoSQL soon. After the refactoring, cycle dependency issue
-	// should be gone and we can use constant at that time
-	if ds.NoSQL.PluginName != "cassandra" {
-		return fmt.Errorf("unknown NoSQL plugin name: %v", ds.NoSQL.PluginName)
+	if ds.NoSQL.PluginName != pluginName {
+		pluginCfg := nosql.PluginConfig{
+			Hosts:                 ds.NoSQL.Hosts,
+			Port:                  ds.NoSQL.Port,
+			User:                  ds.NoSQL.User,
+			Password:              ds.NoSQL.Password,
+			Keyspace:              ds.NoSQL.Keyspace,
+			AllowedAuthenticators: ds.NoSQL.AllowedAuthenticators,
+			Timeout:               DefaultTimeout,
+			ConnectTimeout:        DefaultConnectTimeout,
+			ProtoVersion:          ds.NoSQL.ProtoVersion,
+			TLS:                   ds.NoSQL.TLS,
+		}
+		return nosql.VerifyCompatibleVersion(ds.NoSQL.PluginName, pluginCfg, ds.NoSQL.Keyspace, expectedCassandraVersion)
 	}
 
 	return CheckCompatibleVersion(*ds.NoSQL, expectedCassandraVersion)
@@ -104,7 +126,22 @@ func CheckCompatibleVersion(
 	}
 	defer client.Close()
 
-	return schema.VerifyCompatibleVersion(client, cfg.Keyspace, expectedVersion)
+	reader := NewSchemaVersionReader(client.Session())
+	version, err := reader.ReadSchemaVersion(cfg.Keyspace)
+	if errors.Is(err, ErrGetSchemaVersion) {
+		log.Printf("keyspace %q has no schema_version rows yet, skipping version check", cfg.Keyspace)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if nosql.VersionLess(version, expectedVersion) {
+		return fmt.Errorf(
+			"version mismatch for keyspace %q: expected %v but found %v",
+			cfg.Keyspace, expectedVersion, version,
+		)
+	}
+	return nil
 }
 
 // setupSchema executes the setupSchemaTask
@@ -115,12 +152,16 @@ func setupSchema(cli *cli.Context) error {
 	if err != nil {
 		return handleErr(schema.NewConfigError(err.Error()))
 	}
-	client, err := NewCQLClient(config)
-	if err != nil {
-		return handleErr(err)
+
+	if cli.Bool(schema.CLIOptOverwrite) {
+		if err := confirmDestructive(cli, fmt.Sprintf(
+			"Setting up schema for keyspace %q with --overwrite will drop its existing tables.", config.Keyspace,
+		)); err != nil {
+			return handleErr(err)
+		}
 	}
-	defer client.Close()
-	if err := schema.Setup(cli, client); err != nil {
+
+	if err := nosql.SetupSchema(pluginName, toPluginConfig(*config), cli); err != nil {
 		return handleErr(err)
 	}
 	return nil
@@ -137,8 +178,17 @@ func updateSchema(cli *cli.Context) error {
 	if err != nil {
 		return handleErr(err)
 	}
-	defer client.Close()
-	if err := schema.Update(cli, client); err != nil {
+	reader := NewSchemaVersionReader(client.Session())
+	downgradeErr := checkNotDowngrade(reader, config.Keyspace, cli.String(schema.CLIOptTargetVersion))
+	client.Close()
+	if errors.Is(downgradeErr, ErrConfirmationMissing) {
+		downgradeErr = confirmDestructive(cli, downgradeErr.Error())
+	}
+	if downgradeErr != nil {
+		return handleErr(downgradeErr)
+	}
+
+	if err := nosql.UpdateSchema(pluginName, toPluginConfig(*config), cli); err != nil {
 		return handleErr(err)
 	}
 	return nil
@@ -155,13 +205,34 @@ func createKeyspace(cli *cli.Context) error {
 		return handleErr(schema.NewConfigError("missing " + flag(schema.CLIOptKeyspace) + " argument "))
 	}
 	datacenter := cli.String(schema.CLIOptDatacenter)
-	err = doCreateKeyspace(*config, keyspace, datacenter)
-	if err != nil {
+
+	if exists, err := keyspaceExists(*config, keyspace); err != nil {
+		return handleErr(err)
+	} else if exists {
+		if err := confirmDestructive(cli, fmt.Sprintf("Keyspace %q already exists and will be overwritten.", keyspace)); err != nil {
+			return handleErr(err)
+		}
+	}
+
+	if err := nosql.CreateKeyspace(pluginName, toPluginConfig(*config), keyspace, datacenter); err != nil {
 		return handleErr(fmt.Errorf("error creating Keyspace:%v", err))
 	}
 	return nil
 }
 
+// keyspaceExists reports whether name is already present in the cluster's
+// system_schema.keyspaces, so callers can tell a fresh create from one that
+// would overwrite existing data.
+func keyspaceExists(cfg CQLClientConfig, name string) (bool, error) {
+	cfg.Keyspace = SystemKeyspace
+	client, err := NewCQLClient(&cfg)
+	if err != nil {
+		return false, err
+	}
+	defer client.Close()
+	return client.KeyspaceExists(name)
+}
+
 func doCreateKeyspace(cfg CQLClientConfig, name string, datacenter string) error {
 	cfg.Keyspace = SystemKeyspace
 	client, err := NewCQLClient(&cfg)
@@ -0,0 +1,53 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cassandra
+
+import (
+	"testing"
+
+	"github.com/uber/cadence/common/config"
+)
+
+// TestVerifyCompatibleVersionCheckVisibility pins an unreachable plugin name
+// on the visibility store only, so a probe of it fails immediately (unknown
+// NoSQL plugin) without needing a live cluster. That lets checkVisibility's
+// two behaviors be told apart deterministically: with it false the
+// visibility store must never be probed at all, and with it true the probe
+// must run and its failure must surface.
+func TestVerifyCompatibleVersionCheckVisibility(t *testing.T) {
+	cfg := config.Persistence{
+		DefaultStore:    "default",
+		VisibilityStore: "visibility",
+		DataStores: map[string]config.DataStore{
+			"visibility": {
+				NoSQL: &config.NoSQL{PluginName: "no-such-nosql-plugin"},
+			},
+		},
+	}
+
+	if err := VerifyCompatibleVersion(cfg, false); err != nil {
+		t.Errorf("checkVisibility=false should skip the visibility store probe, got: %v", err)
+	}
+
+	if err := VerifyCompatibleVersion(cfg, true); err == nil {
+		t.Error("checkVisibility=true should probe the visibility store and surface its error")
+	}
+}
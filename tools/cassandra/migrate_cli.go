@@ -0,0 +1,204 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cassandra
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/urfave/cli"
+
+	"github.com/uber/cadence/tools/common/schema"
+)
+
+const (
+	// CLIFlagSourceURL points migrate at a directory/URL of migration files, e.g. file://./schema
+	CLIFlagSourceURL = "source-url"
+	// CLIFlagGotoVersion is the target version for the migrate goto subcommand
+	CLIFlagGotoVersion = "goto-version"
+	// CLIFlagForceVersion is the version for the migrate force subcommand. It is
+	// signed, unlike CLIFlagGotoVersion, since -1 (database.NilVersion) is a
+	// valid value meaning "no migration has ever been applied".
+	CLIFlagForceVersion = "force-version"
+)
+
+// migrateURL builds the cassandra:// DSN migrateDriver.Open expects from the
+// same CQLClientConfig the rest of this package's commands use, carrying
+// auth, protocol version, and TLS settings as userinfo/query parameters so
+// `migrate` connects exactly the way setup-schema/update-schema do instead of
+// silently falling back to an unauthenticated, unencrypted connection.
+func migrateURL(cfg *CQLClientConfig) string {
+	u := url.URL{
+		Scheme: "cassandra",
+		Host:   fmt.Sprintf("%s:%d", cfg.Hosts, cfg.Port),
+		Path:   "/" + cfg.Keyspace,
+	}
+	if cfg.User != "" {
+		u.User = url.UserPassword(cfg.User, cfg.Password)
+	}
+
+	q := u.Query()
+	q.Set("x-multi-statement", "true")
+	q.Set("x-migrations-table", defaultMigrationsTable)
+	if cfg.ProtoVersion != 0 {
+		q.Set("x-proto-version", strconv.Itoa(cfg.ProtoVersion))
+	}
+	for _, a := range cfg.AllowedAuthenticators {
+		q.Add("x-allowed-authenticator", a)
+	}
+	if cfg.TLS != nil && cfg.TLS.Enabled {
+		q.Set("x-tls", "true")
+		q.Set("x-tls-cert-file", cfg.TLS.CertFile)
+		q.Set("x-tls-key-file", cfg.TLS.KeyFile)
+		q.Set("x-tls-ca-file", cfg.TLS.CaFile)
+		q.Set("x-tls-server-name", cfg.TLS.ServerName)
+		if cfg.TLS.EnableHostVerification {
+			q.Set("x-tls-host-verification", "true")
+		}
+	}
+	u.RawQuery = q.Encode()
+
+	return u.String()
+}
+
+func newMigrate(cli *cli.Context) (*migrate.Migrate, error) {
+	cfg, err := newCQLClientConfig(cli)
+	if err != nil {
+		return nil, schema.NewConfigError(err.Error())
+	}
+	sourceURL := cli.String(CLIFlagSourceURL)
+	if sourceURL == "" {
+		return nil, schema.NewConfigError("missing " + flag(CLIFlagSourceURL) + " argument ")
+	}
+	return migrate.New(sourceURL, migrateURL(cfg))
+}
+
+// migrateUp runs the `cadence-cassandra-tool migrate up` subcommand, applying
+// all pending migrations.
+func migrateUp(cli *cli.Context) error {
+	m, err := newMigrate(cli)
+	if err != nil {
+		return handleErr(err)
+	}
+	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
+		return handleErr(err)
+	}
+	return nil
+}
+
+// migrateDown runs the `cadence-cassandra-tool migrate down` subcommand,
+// rolling back all applied migrations.
+func migrateDown(cli *cli.Context) error {
+	m, err := newMigrate(cli)
+	if err != nil {
+		return handleErr(err)
+	}
+	if err := m.Down(); err != nil && err != migrate.ErrNoChange {
+		return handleErr(err)
+	}
+	return nil
+}
+
+// migrateGoto runs the `cadence-cassandra-tool migrate goto` subcommand,
+// moving the schema directly to CLIFlagGotoVersion.
+func migrateGoto(cli *cli.Context) error {
+	m, err := newMigrate(cli)
+	if err != nil {
+		return handleErr(err)
+	}
+	version := cli.Uint(CLIFlagGotoVersion)
+	if err := m.Migrate(uint(version)); err != nil && err != migrate.ErrNoChange {
+		return handleErr(err)
+	}
+	return nil
+}
+
+// migrateForce runs the `cadence-cassandra-tool migrate force` subcommand,
+// clearing the dirty flag left behind by a failed migration without
+// re-running it.
+func migrateForce(cli *cli.Context) error {
+	m, err := newMigrate(cli)
+	if err != nil {
+		return handleErr(err)
+	}
+	version := cli.Int(CLIFlagForceVersion)
+	if err := m.Force(version); err != nil {
+		return handleErr(err)
+	}
+	return nil
+}
+
+// MigrateCommand returns the cli.Command for `cadence-cassandra-tool
+// migrate`, ready to be added to that tool's cli.App.Commands alongside
+// setup-schema/update-schema/rolling-update-schema. It wraps golang-migrate's
+// up/down/goto/force operations over the cassandra database.Driver registered
+// in migrate_driver.go.
+func MigrateCommand() cli.Command {
+	sourceURLFlag := cli.StringFlag{
+		Name:  CLIFlagSourceURL,
+		Usage: "directory/URL of migration files, e.g. file://./schema",
+	}
+	return cli.Command{
+		Name:  "migrate",
+		Usage: "applies golang-migrate schema migrations from --source-url",
+		Subcommands: []cli.Command{
+			{
+				Name:   "up",
+				Usage:  "applies all pending migrations",
+				Flags:  []cli.Flag{sourceURLFlag},
+				Action: migrateUp,
+			},
+			{
+				Name:   "down",
+				Usage:  "rolls back all applied migrations",
+				Flags:  []cli.Flag{sourceURLFlag},
+				Action: migrateDown,
+			},
+			{
+				Name:  "goto",
+				Usage: "migrates directly to --goto-version",
+				Flags: []cli.Flag{
+					sourceURLFlag,
+					cli.UintFlag{
+						Name:  CLIFlagGotoVersion,
+						Usage: "the schema version to migrate to",
+					},
+				},
+				Action: migrateGoto,
+			},
+			{
+				Name:  "force",
+				Usage: "clears the dirty flag left behind by a failed migration without re-running it",
+				Flags: []cli.Flag{
+					sourceURLFlag,
+					cli.IntFlag{
+						Name:  CLIFlagForceVersion,
+						Usage: "the version to force the schema to (-1 for database.NilVersion)",
+					},
+				},
+				Action: migrateForce,
+			},
+		},
+	}
+}
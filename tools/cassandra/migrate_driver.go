@@ -0,0 +1,271 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cassandra
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/golang-migrate/migrate/v4/database"
+
+	"github.com/uber/cadence/common/config"
+)
+
+const defaultMigrationsTable = "schema_migrations"
+
+func init() {
+	database.Register("cassandra", &migrateDriver{})
+}
+
+// migrationStore is the narrow slice of CQLClient that migrateDriver needs.
+// Declaring it separately, rather than holding a CQLClient directly, keeps
+// SetVersion/Version/Drop testable with a fake instead of a live cluster.
+type migrationStore interface {
+	Exec(stmt string) error
+	ReadMigrationVersion(migrationsTable string) (version int, dirty bool, ok bool, err error)
+	ListTables() ([]string, error)
+	Close()
+}
+
+// migrateDriver adapts CQLClient to github.com/golang-migrate/migrate's
+// database.Driver interface, so `cadence-cassandra-tool migrate` gets the
+// same up/down/goto/force UX as migrate's other backends while reusing
+// Cadence's existing CQL connection, TLS, and auth configuration.
+//
+// Cassandra has no cross-cluster advisory lock primitive, so Lock/Unlock are
+// no-ops; concurrent migrate runs against the same keyspace are the caller's
+// responsibility to serialize.
+type migrateDriver struct {
+	client          migrationStore
+	migrationsTable string
+	multiStatement  bool
+}
+
+// parsedMigrateURL is the result of parsing a cassandra:// migrate DSN: the
+// CQLClientConfig to dial with -- including auth, protocol version, and TLS,
+// carried as userinfo/query parameters by migrateURL -- plus the driver
+// options carried alongside them.
+type parsedMigrateURL struct {
+	clientConfig    CQLClientConfig
+	migrationsTable string
+	multiStatement  bool
+}
+
+// parseMigrateURL parses a URL of the form
+// cassandra://[user:pass@]host:9042/keyspace?x-multi-statement=true&x-migrations-table=schema_migrations&x-proto-version=4&x-tls=true&...
+// into the CQLClientConfig and driver options Open needs, so `migrate`
+// connects exactly the way setup-schema/update-schema do instead of silently
+// dropping auth/TLS settings on the floor.
+func parseMigrateURL(dsn string) (*parsedMigrateURL, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cassandra migrate URL: %v", err)
+	}
+
+	host, portStr, err := net.SplitHostPort(u.Host)
+	if err != nil {
+		host = u.Host
+		portStr = ""
+	}
+	port := DefaultCassandraPort
+	if portStr != "" {
+		if p, err := strconv.Atoi(portStr); err == nil {
+			port = p
+		}
+	}
+
+	keyspace := strings.TrimPrefix(u.Path, "/")
+	if keyspace == "" {
+		return nil, fmt.Errorf("cassandra migrate URL must include a keyspace path, e.g. cassandra://host:9042/keyspace")
+	}
+
+	query := u.Query()
+	migrationsTable := query.Get("x-migrations-table")
+	if migrationsTable == "" {
+		migrationsTable = defaultMigrationsTable
+	}
+
+	cfg := CQLClientConfig{
+		Hosts:                 host,
+		Port:                  port,
+		Keyspace:              keyspace,
+		Timeout:               DefaultTimeout,
+		ConnectTimeout:        DefaultConnectTimeout,
+		AllowedAuthenticators: query["x-allowed-authenticator"],
+	}
+	if u.User != nil {
+		cfg.User = u.User.Username()
+		cfg.Password, _ = u.User.Password()
+	}
+	if v := query.Get("x-proto-version"); v != "" {
+		if p, err := strconv.Atoi(v); err == nil {
+			cfg.ProtoVersion = p
+		}
+	}
+	if query.Get("x-tls") == "true" {
+		cfg.TLS = &config.TLS{
+			Enabled:                true,
+			CertFile:               query.Get("x-tls-cert-file"),
+			KeyFile:                query.Get("x-tls-key-file"),
+			CaFile:                 query.Get("x-tls-ca-file"),
+			ServerName:             query.Get("x-tls-server-name"),
+			EnableHostVerification: query.Get("x-tls-host-verification") == "true",
+		}
+	}
+
+	return &parsedMigrateURL{
+		clientConfig:    cfg,
+		migrationsTable: migrationsTable,
+		multiStatement:  query.Get("x-multi-statement") == "true",
+	}, nil
+}
+
+// Open parses dsn (see parseMigrateURL) and returns a migrateDriver connected
+// to the resulting keyspace, creating its schema_migrations table if it does
+// not already exist.
+func (d *migrateDriver) Open(dsn string) (database.Driver, error) {
+	parsed, err := parseMigrateURL(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := NewCQLClient(&parsed.clientConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	drv := &migrateDriver{
+		client:          client,
+		migrationsTable: parsed.migrationsTable,
+		multiStatement:  parsed.multiStatement,
+	}
+	if err := drv.ensureMigrationsTable(); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return drv, nil
+}
+
+func (d *migrateDriver) ensureMigrationsTable() error {
+	return d.client.Exec(fmt.Sprintf(
+		`CREATE TABLE IF NOT EXISTS %s (version bigint PRIMARY KEY, dirty boolean)`,
+		d.migrationsTable,
+	))
+}
+
+// Lock is a no-op: Cassandra has no cluster-wide advisory lock primitive.
+func (d *migrateDriver) Lock() error { return nil }
+
+// Unlock is a no-op; see Lock.
+func (d *migrateDriver) Unlock() error { return nil }
+
+// Run executes a single migration file's contents. When the driver was
+// opened with x-multi-statement=true, the contents are split on ';' and each
+// statement is executed in turn; otherwise the whole body is sent as-is.
+func (d *migrateDriver) Run(migration io.Reader) error {
+	body, err := ioutil.ReadAll(migration)
+	if err != nil {
+		return err
+	}
+
+	statements := []string{string(body)}
+	if d.multiStatement {
+		statements = splitStatements(string(body))
+	}
+
+	for _, stmt := range statements {
+		stmt = strings.TrimSpace(stmt)
+		if stmt == "" {
+			continue
+		}
+		if err := d.client.Exec(stmt); err != nil {
+			return fmt.Errorf("migration failed executing %q: %v", stmt, err)
+		}
+	}
+	return nil
+}
+
+// splitStatements splits a multi-statement .cql file body on ';', dropping
+// empty fragments produced by a trailing terminator or blank lines.
+func splitStatements(body string) []string {
+	parts := strings.Split(body, ";")
+	statements := make([]string, 0, len(parts))
+	for _, p := range parts {
+		if strings.TrimSpace(p) != "" {
+			statements = append(statements, p)
+		}
+	}
+	return statements
+}
+
+// SetVersion records version as the current schema version, marking it dirty
+// if a migration step is in progress or just failed. Only the latest version
+// is kept; on failure the row is left dirty so subsequent runs must be
+// explicitly forced past it.
+func (d *migrateDriver) SetVersion(version int, dirty bool) error {
+	if err := d.client.Exec(fmt.Sprintf(`TRUNCATE %s`, d.migrationsTable)); err != nil {
+		return err
+	}
+	return d.client.Exec(fmt.Sprintf(
+		`INSERT INTO %s (version, dirty) VALUES (%d, %v)`,
+		d.migrationsTable, version, dirty,
+	))
+}
+
+// Version returns the current schema version and dirty flag, or
+// database.NilVersion if no migration has run yet.
+func (d *migrateDriver) Version() (int, bool, error) {
+	version, dirty, ok, err := d.client.ReadMigrationVersion(d.migrationsTable)
+	if err != nil {
+		return 0, false, err
+	}
+	if !ok {
+		return database.NilVersion, false, nil
+	}
+	return version, dirty, nil
+}
+
+// Drop removes every table in the driver's keyspace, including the
+// migrations table itself, and re-creates the migrations table so the
+// keyspace can be migrated from scratch.
+func (d *migrateDriver) Drop() error {
+	tables, err := d.client.ListTables()
+	if err != nil {
+		return err
+	}
+	for _, t := range tables {
+		if err := d.client.Exec(fmt.Sprintf(`DROP TABLE IF EXISTS %s`, t)); err != nil {
+			return err
+		}
+	}
+	return d.ensureMigrationsTable()
+}
+
+// Close releases the underlying CQL session.
+func (d *migrateDriver) Close() error {
+	d.client.Close()
+	return nil
+}
@@ -0,0 +1,239 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cassandra
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/golang-migrate/migrate/v4/database"
+
+	"github.com/uber/cadence/common/config"
+)
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []string
+	}{
+		{name: "single statement", body: "CREATE TABLE foo (id int)", want: []string{"CREATE TABLE foo (id int)"}},
+		{name: "trailing terminator dropped", body: "CREATE TABLE foo (id int);", want: []string{"CREATE TABLE foo (id int)"}},
+		{name: "multiple statements", body: "CREATE TABLE foo (id int); CREATE TABLE bar (id int);", want: []string{"CREATE TABLE foo (id int)", " CREATE TABLE bar (id int)"}},
+		{name: "blank fragments dropped", body: "CREATE TABLE foo (id int);;  ;", want: []string{"CREATE TABLE foo (id int)"}},
+		{name: "empty body", body: "", want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := splitStatements(tt.body)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitStatements(%q) = %#v, want %#v", tt.body, got, tt.want)
+			}
+		})
+	}
+}
+
+type fakeMigrationStore struct {
+	execStatements  []string
+	execErr         error
+	version         int
+	dirty           bool
+	versionOK       bool
+	readVersionErr  error
+	tables          []string
+	listTablesErr   error
+	migrationsTable string
+}
+
+func (f *fakeMigrationStore) Exec(stmt string) error {
+	f.execStatements = append(f.execStatements, stmt)
+	return f.execErr
+}
+
+func (f *fakeMigrationStore) ReadMigrationVersion(migrationsTable string) (int, bool, bool, error) {
+	f.migrationsTable = migrationsTable
+	return f.version, f.dirty, f.versionOK, f.readVersionErr
+}
+
+func (f *fakeMigrationStore) ListTables() ([]string, error) {
+	return f.tables, f.listTablesErr
+}
+
+func (f *fakeMigrationStore) Close() {}
+
+func TestMigrateDriverSetVersion(t *testing.T) {
+	store := &fakeMigrationStore{}
+	d := &migrateDriver{client: store, migrationsTable: "schema_migrations"}
+
+	if err := d.SetVersion(3, true); err != nil {
+		t.Fatalf("SetVersion() error = %v", err)
+	}
+	want := []string{
+		"TRUNCATE schema_migrations",
+		"INSERT INTO schema_migrations (version, dirty) VALUES (3, true)",
+	}
+	if !reflect.DeepEqual(store.execStatements, want) {
+		t.Errorf("executed statements = %#v, want %#v", store.execStatements, want)
+	}
+}
+
+func TestMigrateDriverSetVersionTruncateFails(t *testing.T) {
+	store := &fakeMigrationStore{execErr: errors.New("truncate failed")}
+	d := &migrateDriver{client: store, migrationsTable: "schema_migrations"}
+
+	if err := d.SetVersion(3, true); err == nil {
+		t.Fatal("expected an error when TRUNCATE fails")
+	}
+	if len(store.execStatements) != 1 {
+		t.Errorf("expected SetVersion to stop after the failing TRUNCATE, got %v", store.execStatements)
+	}
+}
+
+func TestMigrateDriverVersion(t *testing.T) {
+	tests := []struct {
+		name        string
+		store       *fakeMigrationStore
+		wantVersion int
+		wantDirty   bool
+		wantErr     bool
+	}{
+		{name: "no migrations run yet", store: &fakeMigrationStore{versionOK: false}, wantVersion: database.NilVersion},
+		{name: "version present", store: &fakeMigrationStore{version: 5, dirty: true, versionOK: true}, wantVersion: 5, wantDirty: true},
+		{name: "read error propagates", store: &fakeMigrationStore{readVersionErr: errors.New("read failed")}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &migrateDriver{client: tt.store, migrationsTable: "schema_migrations"}
+			version, dirty, err := d.Version()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Version() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if version != tt.wantVersion || dirty != tt.wantDirty {
+				t.Errorf("Version() = (%v, %v), want (%v, %v)", version, dirty, tt.wantVersion, tt.wantDirty)
+			}
+		})
+	}
+}
+
+func TestMigrateDriverDrop(t *testing.T) {
+	store := &fakeMigrationStore{tables: []string{"foo", "bar"}}
+	d := &migrateDriver{client: store, migrationsTable: "schema_migrations"}
+
+	if err := d.Drop(); err != nil {
+		t.Fatalf("Drop() error = %v", err)
+	}
+	want := []string{
+		"DROP TABLE IF EXISTS foo",
+		"DROP TABLE IF EXISTS bar",
+		"CREATE TABLE IF NOT EXISTS schema_migrations (version bigint PRIMARY KEY, dirty boolean)",
+	}
+	if !reflect.DeepEqual(store.execStatements, want) {
+		t.Errorf("executed statements = %#v, want %#v", store.execStatements, want)
+	}
+}
+
+func TestMigrateDriverDropListTablesFails(t *testing.T) {
+	store := &fakeMigrationStore{listTablesErr: errors.New("list failed")}
+	d := &migrateDriver{client: store, migrationsTable: "schema_migrations"}
+
+	if err := d.Drop(); err == nil {
+		t.Fatal("expected an error when ListTables fails")
+	}
+}
+
+func TestMigrateURLRoundTripsConnectionConfig(t *testing.T) {
+	cfg := &CQLClientConfig{
+		Hosts:                 "cassandra.internal",
+		Port:                  9142,
+		Keyspace:              "cadence",
+		User:                  "cadence",
+		Password:              "s3cr3t",
+		AllowedAuthenticators: []string{"org.apache.cassandra.auth.PasswordAuthenticator"},
+		ProtoVersion:          4,
+		TLS: &config.TLS{
+			Enabled:                true,
+			CertFile:               "/etc/cassandra/cert.pem",
+			KeyFile:                "/etc/cassandra/key.pem",
+			CaFile:                 "/etc/cassandra/ca.pem",
+			ServerName:             "cassandra.internal",
+			EnableHostVerification: true,
+		},
+	}
+
+	parsed, err := parseMigrateURL(migrateURL(cfg))
+	if err != nil {
+		t.Fatalf("parseMigrateURL() error = %v", err)
+	}
+
+	got := parsed.clientConfig
+	if got.Hosts != cfg.Hosts || got.Port != cfg.Port || got.Keyspace != cfg.Keyspace {
+		t.Errorf("connection target = %+v, want host/port/keyspace from %+v", got, cfg)
+	}
+	if got.User != cfg.User || got.Password != cfg.Password {
+		t.Errorf("auth = %+v, want user/password from %+v", got, cfg)
+	}
+	if !reflect.DeepEqual(got.AllowedAuthenticators, cfg.AllowedAuthenticators) {
+		t.Errorf("AllowedAuthenticators = %v, want %v", got.AllowedAuthenticators, cfg.AllowedAuthenticators)
+	}
+	if got.ProtoVersion != cfg.ProtoVersion {
+		t.Errorf("ProtoVersion = %v, want %v", got.ProtoVersion, cfg.ProtoVersion)
+	}
+	if !reflect.DeepEqual(got.TLS, cfg.TLS) {
+		t.Errorf("TLS = %+v, want %+v", got.TLS, cfg.TLS)
+	}
+	if !parsed.multiStatement {
+		t.Error("expected x-multi-statement=true to round-trip")
+	}
+}
+
+func TestMigrateURLOmitsAuthAndTLSWhenUnset(t *testing.T) {
+	cfg := &CQLClientConfig{Hosts: "localhost", Port: 9042, Keyspace: "cadence"}
+
+	parsed, err := parseMigrateURL(migrateURL(cfg))
+	if err != nil {
+		t.Fatalf("parseMigrateURL() error = %v", err)
+	}
+	if parsed.clientConfig.User != "" || parsed.clientConfig.TLS != nil {
+		t.Errorf("expected no auth/TLS to round-trip, got %+v", parsed.clientConfig)
+	}
+}
+
+func TestParseMigrateURLMissingKeyspace(t *testing.T) {
+	if _, err := parseMigrateURL("cassandra://host:9042/"); err == nil {
+		t.Fatal("expected an error for a missing keyspace")
+	}
+}
+
+func TestParseMigrateURLDefaultsMigrationsTable(t *testing.T) {
+	parsed, err := parseMigrateURL("cassandra://host:9042/ks")
+	if err != nil {
+		t.Fatalf("parseMigrateURL() error = %v", err)
+	}
+	if parsed.migrationsTable != defaultMigrationsTable {
+		t.Errorf("migrationsTable = %q, want %q", parsed.migrationsTable, defaultMigrationsTable)
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cassandra
+
+import (
+	"errors"
+
+	"github.com/uber/cadence/tools/common/schema"
+	"github.com/uber/cadence/tools/nosql"
+)
+
+// pluginName is the config.NoSQL.PluginName this package registers itself
+// under with the tools/nosql plugin registry.
+const pluginName = "cassandra"
+
+func init() {
+	nosql.RegisterPlugin(pluginName, &schemaPlugin{})
+}
+
+// schemaPlugin adapts CQLClient to the driver-agnostic nosql.NoSQLPlugin
+// interface so tools/nosql can dispatch schema operations to Cassandra by
+// plugin name instead of this package being hardcoded as the only option.
+type schemaPlugin struct{}
+
+// toCQLClientConfig translates the driver-agnostic nosql.PluginConfig into
+// the CQLClientConfig NewCQLClient expects, so every schemaPlugin method
+// actually dials the cluster the caller asked for instead of a zero-valued one.
+func toCQLClientConfig(cfg nosql.PluginConfig) *CQLClientConfig {
+	return &CQLClientConfig{
+		Hosts:                 cfg.Hosts,
+		Port:                  cfg.Port,
+		User:                  cfg.User,
+		Password:              cfg.Password,
+		Keyspace:              cfg.Keyspace,
+		AllowedAuthenticators: cfg.AllowedAuthenticators,
+		Timeout:               cfg.Timeout,
+		ConnectTimeout:        cfg.ConnectTimeout,
+		ProtoVersion:          cfg.ProtoVersion,
+		NumReplicas:           cfg.NumReplicas,
+		TLS:                   cfg.TLS,
+	}
+}
+
+// toPluginConfig translates a CQLClientConfig into the driver-agnostic
+// nosql.PluginConfig, the inverse of toCQLClientConfig. Callers in this
+// package that resolved a CQLClientConfig from CLI flags use this to drive
+// the shared tools/nosql handlers without losing connection details.
+func toPluginConfig(cfg CQLClientConfig) nosql.PluginConfig {
+	return nosql.PluginConfig{
+		Hosts:                 cfg.Hosts,
+		Port:                  cfg.Port,
+		User:                  cfg.User,
+		Password:              cfg.Password,
+		Keyspace:              cfg.Keyspace,
+		AllowedAuthenticators: cfg.AllowedAuthenticators,
+		Timeout:               cfg.Timeout,
+		ConnectTimeout:        cfg.ConnectTimeout,
+		ProtoVersion:          cfg.ProtoVersion,
+		NumReplicas:           cfg.NumReplicas,
+		TLS:                   cfg.TLS,
+	}
+}
+
+func (p *schemaPlugin) CreateKeyspace(cfg nosql.PluginConfig, name string, datacenter string) error {
+	return doCreateKeyspace(*toCQLClientConfig(cfg), name, datacenter)
+}
+
+// ReadSchemaVersion returns keyspace's recorded schema version. It translates
+// this package's ErrGetSchemaVersion into nosql.ErrSchemaVersionNotFound so
+// tools/nosql.VerifyCompatibleVersion can recognize "no rows yet" without
+// importing this package back (which would create an import cycle).
+func (p *schemaPlugin) ReadSchemaVersion(cfg nosql.PluginConfig, keyspace string) (string, error) {
+	clientCfg := toCQLClientConfig(cfg)
+	clientCfg.Keyspace = keyspace
+	client, err := NewCQLClient(clientCfg)
+	if err != nil {
+		return "", err
+	}
+	defer client.Close()
+	version, err := NewSchemaVersionReader(client.Session()).ReadSchemaVersion(keyspace)
+	if errors.Is(err, ErrGetSchemaVersion) {
+		return "", nosql.ErrSchemaVersionNotFound
+	}
+	return version, err
+}
+
+// NewDB opens a single CQLClient bound to cfg and hands it back as a
+// schema.DB: CQLClient already implements that interface (version-table
+// bookkeeping, dropping all tables on --overwrite, update history), and
+// returning the live client -- instead of a per-statement shim -- means
+// Setup/Update reuse one connection for every statement they run, rather
+// than racing Cassandra's schema agreement with a fresh handshake each time.
+func (p *schemaPlugin) NewDB(cfg nosql.PluginConfig) (schema.DB, error) {
+	return NewCQLClient(toCQLClientConfig(cfg))
+}
+
+func (p *schemaPlugin) Close() {}
@@ -0,0 +1,292 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cassandra
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/gocql/gocql"
+	"github.com/urfave/cli"
+
+	"github.com/uber/cadence/tools/common/schema"
+	"github.com/uber/cadence/tools/nosql"
+)
+
+const (
+	// CLIFlagTargetVersion is the schema version the rollout should converge the cluster on
+	CLIFlagTargetVersion = "target-version"
+	// CLIFlagMinNodeVersion is the lowest Cassandra release_version every node must report before the rollout proceeds
+	CLIFlagMinNodeVersion = "min-node-version"
+	// CLIFlagDryRun reports the rollout plan and node status without applying any migrations
+	CLIFlagDryRun = "dry-run"
+	// CLIFlagVerifyCQL is a CQL query run after every version increment is applied; the rollout
+	// stops if it errors, so operators can catch a bad migration before it spreads further
+	CLIFlagVerifyCQL = "verify-cql"
+)
+
+// NodeStatus captures a single cluster member's reported release_version, as
+// read from system.local/system.peers, and whether it satisfies minNodeVersion.
+type NodeStatus struct {
+	Host           string
+	ReleaseVersion string
+	OK             bool
+}
+
+// RollingUpgrader coordinates a schema upgrade the way a Cassandra operator
+// rolls out node versions: it refuses to advance the schema while any node in
+// the cluster lags the configured minimum release, and otherwise applies the
+// migration directories under schemaDir one version increment at a time,
+// running verifyCQL after each step before moving to the next. Each
+// RollingUpgrader upgrades only its one keyspace; other keyspaces are not
+// touched.
+type RollingUpgrader struct {
+	client         *CQLClient
+	session        *gocql.Session
+	keyspace       string
+	schemaDir      string
+	targetVersion  string
+	minNodeVersion string
+	dryRun         bool
+}
+
+// NewRollingUpgrader creates a RollingUpgrader that verifies cluster node
+// versions before applying, one version at a time, the migrations found
+// under schemaDir up to targetVersion.
+func NewRollingUpgrader(
+	client *CQLClient,
+	session *gocql.Session,
+	keyspace string,
+	schemaDir string,
+	targetVersion string,
+	minNodeVersion string,
+	dryRun bool,
+) *RollingUpgrader {
+	return &RollingUpgrader{
+		client:         client,
+		session:        session,
+		keyspace:       keyspace,
+		schemaDir:      schemaDir,
+		targetVersion:  targetVersion,
+		minNodeVersion: minNodeVersion,
+		dryRun:         dryRun,
+	}
+}
+
+// CheckNodeVersions queries every reachable node's release_version via
+// system.local and system.peers and reports whether each one meets
+// minNodeVersion. The rollout must not proceed while any node is lagging.
+func (r *RollingUpgrader) CheckNodeVersions() ([]NodeStatus, error) {
+	var statuses []NodeStatus
+
+	var localVersion string
+	localIter := r.session.Query(`SELECT release_version FROM system.local`).Iter()
+	for localIter.Scan(&localVersion) {
+		statuses = append(statuses, NodeStatus{
+			Host:           "local",
+			ReleaseVersion: localVersion,
+			OK:             !nosql.VersionLess(localVersion, r.minNodeVersion),
+		})
+	}
+	if err := localIter.Close(); err != nil {
+		return nil, fmt.Errorf("unable to query system.local: %v", err)
+	}
+
+	var peer, peerVersion string
+	peerIter := r.session.Query(`SELECT peer, release_version FROM system.peers`).Iter()
+	for peerIter.Scan(&peer, &peerVersion) {
+		statuses = append(statuses, NodeStatus{
+			Host:           peer,
+			ReleaseVersion: peerVersion,
+			OK:             !nosql.VersionLess(peerVersion, r.minNodeVersion),
+		})
+	}
+	if err := peerIter.Close(); err != nil {
+		return nil, fmt.Errorf("unable to query system.peers: %v", err)
+	}
+
+	return statuses, nil
+}
+
+// pendingVersions lists the schema versions under r.schemaDir that are newer
+// than currentVersion and no newer than r.targetVersion, in the order they
+// must be applied.
+func (r *RollingUpgrader) pendingVersions(currentVersion string) ([]string, error) {
+	entries, err := ioutil.ReadDir(r.schemaDir)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list schema directory %q: %v", r.schemaDir, err)
+	}
+
+	var versions []string
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		v := strings.TrimPrefix(e.Name(), "v")
+		if nosql.VersionLess(currentVersion, v) && !nosql.VersionLess(r.targetVersion, v) {
+			versions = append(versions, v)
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool { return nosql.VersionLess(versions[i], versions[j]) })
+	return versions, nil
+}
+
+// Run verifies every node meets minNodeVersion and, if so, applies each
+// pending migration version under schemaDir in order, running verifyCQL
+// (when non-empty) after every single increment and stopping at the first
+// failure instead of attempting the whole upgrade as one all-or-nothing
+// step. In dry-run mode it only reports node status and the versions that
+// would be applied.
+func (r *RollingUpgrader) Run(verifyCQL string) ([]NodeStatus, error) {
+	statuses, err := r.CheckNodeVersions()
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range statuses {
+		if !s.OK {
+			return statuses, fmt.Errorf(
+				"node %v is at release_version %v, below required minimum %v; refusing to advance schema",
+				s.Host, s.ReleaseVersion, r.minNodeVersion,
+			)
+		}
+	}
+
+	reader := NewSchemaVersionReader(r.session)
+	currentVersion, err := reader.ReadSchemaVersion(r.keyspace)
+	if err != nil {
+		if !errors.Is(err, ErrGetSchemaVersion) {
+			return statuses, fmt.Errorf("unable to read current schema version: %v", err)
+		}
+		currentVersion = ""
+	}
+
+	versions, err := r.pendingVersions(currentVersion)
+	if err != nil {
+		return statuses, err
+	}
+
+	if r.dryRun {
+		log.Printf("dry-run: would apply versions %v to keyspace %q", versions, r.keyspace)
+		return statuses, nil
+	}
+
+	for _, version := range versions {
+		updateTask, err := schema.NewUpdateSchemaTask(r.client, &schema.UpdateConfig{
+			TargetVersion: version,
+			SchemaDir:     r.schemaDir,
+			IsDryRun:      false,
+		})
+		if err != nil {
+			return statuses, fmt.Errorf("unable to prepare update to version %v: %v", version, err)
+		}
+		if err := updateTask.Run(); err != nil {
+			return statuses, fmt.Errorf("update to version %v failed: %v", version, err)
+		}
+
+		if verifyCQL != "" {
+			if err := r.session.Query(verifyCQL).Exec(); err != nil {
+				return statuses, fmt.Errorf("verification query failed after applying version %v: %v", version, err)
+			}
+		}
+		log.Printf("applied schema version %v to keyspace %q", version, r.keyspace)
+	}
+
+	return statuses, nil
+}
+
+// rollingUpdateSchema executes a RollingUpgrader using the given command line
+// arguments as input. It backs the `cadence-cassandra-tool rolling-update-schema`
+// subcommand.
+func rollingUpdateSchema(cli *cli.Context) error {
+	config, err := newCQLClientConfig(cli)
+	if err != nil {
+		return handleErr(schema.NewConfigError(err.Error()))
+	}
+	client, err := NewCQLClient(config)
+	if err != nil {
+		return handleErr(err)
+	}
+	defer client.Close()
+
+	targetVersion := cli.String(CLIFlagTargetVersion)
+	if targetVersion == "" {
+		return handleErr(schema.NewConfigError("missing " + flag(CLIFlagTargetVersion) + " argument "))
+	}
+	minNodeVersion := cli.String(CLIFlagMinNodeVersion)
+	if minNodeVersion == "" {
+		return handleErr(schema.NewConfigError("missing " + flag(CLIFlagMinNodeVersion) + " argument "))
+	}
+	schemaDir := cli.String(schema.CLIOptSchemaDir)
+	if schemaDir == "" {
+		return handleErr(schema.NewConfigError("missing " + flag(schema.CLIOptSchemaDir) + " argument "))
+	}
+
+	upgrader := NewRollingUpgrader(
+		client, client.Session(), config.Keyspace, schemaDir,
+		targetVersion, minNodeVersion, cli.Bool(CLIFlagDryRun),
+	)
+	statuses, err := upgrader.Run(cli.String(CLIFlagVerifyCQL))
+	for _, s := range statuses {
+		log.Printf("node %v: release_version=%v ok=%v", s.Host, s.ReleaseVersion, s.OK)
+	}
+	if err != nil {
+		return handleErr(err)
+	}
+	return nil
+}
+
+// RollingUpdateSchemaCommand returns the cli.Command for
+// `cadence-cassandra-tool rolling-update-schema`, ready to be added to that
+// tool's cli.App.Commands alongside setup-schema/update-schema/create-Keyspace.
+func RollingUpdateSchemaCommand() cli.Command {
+	return cli.Command{
+		Name:        "rolling-update-schema",
+		Usage:       "rolls the schema forward one version at a time, refusing to proceed while any node lags --min-node-version",
+		Description: "Coordinates a multi-node schema upgrade: verifies every reachable node's release_version, then applies migration directories one increment at a time, running --verify-cql after each step.",
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  schema.CLIOptSchemaDir,
+				Usage: "the directory holding one v<version> subdirectory of migrations per schema version",
+			},
+			cli.StringFlag{
+				Name:  CLIFlagTargetVersion,
+				Usage: "the schema version to converge the cluster on",
+			},
+			cli.StringFlag{
+				Name:  CLIFlagMinNodeVersion,
+				Usage: "the lowest Cassandra release_version every node must report before the rollout proceeds",
+			},
+			cli.BoolFlag{
+				Name:  CLIFlagDryRun,
+				Usage: "report node status and the versions that would be applied without changing anything",
+			},
+			cli.StringFlag{
+				Name:  CLIFlagVerifyCQL,
+				Usage: "a CQL query run after each version increment; the rollout stops if it errors",
+			},
+		},
+		Action: rollingUpdateSchema,
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cassandra
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// makeSchemaDir creates a temp directory containing one subdirectory per
+// version name (plus any extra non-version entries), for pendingVersions to
+// list.
+func makeSchemaDir(t *testing.T, dirs []string, files []string) string {
+	t.Helper()
+	root := t.TempDir()
+	for _, d := range dirs {
+		if err := os.Mkdir(filepath.Join(root, d), 0755); err != nil {
+			t.Fatalf("unable to create %q: %v", d, err)
+		}
+	}
+	for _, f := range files {
+		if err := os.WriteFile(filepath.Join(root, f), nil, 0644); err != nil {
+			t.Fatalf("unable to create %q: %v", f, err)
+		}
+	}
+	return root
+}
+
+func TestRollingUpgraderPendingVersions(t *testing.T) {
+	tests := []struct {
+		name           string
+		dirs           []string
+		files          []string
+		currentVersion string
+		targetVersion  string
+		want           []string
+	}{
+		{
+			name:           "filters to the window and sorts numerically",
+			dirs:           []string{"v0.1", "v0.10", "v0.2", "v0.9"},
+			currentVersion: "0.1",
+			targetVersion:  "0.10",
+			want:           []string{"0.2", "0.9", "0.10"},
+		},
+		{
+			name:           "nothing newer than current is pending",
+			dirs:           []string{"v0.1", "v0.2"},
+			currentVersion: "0.2",
+			targetVersion:  "0.2",
+			want:           nil,
+		},
+		{
+			name:           "versions past the target are excluded",
+			dirs:           []string{"v0.1", "v0.2", "v0.3"},
+			currentVersion: "0.1",
+			targetVersion:  "0.2",
+			want:           []string{"0.2"},
+		},
+		{
+			name:           "non-directory entries are ignored",
+			dirs:           []string{"v0.1", "v0.2"},
+			files:          []string{"README.md"},
+			currentVersion: "0.1",
+			targetVersion:  "0.2",
+			want:           []string{"0.2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schemaDir := makeSchemaDir(t, tt.dirs, tt.files)
+			r := &RollingUpgrader{schemaDir: schemaDir, targetVersion: tt.targetVersion}
+
+			got, err := r.pendingVersions(tt.currentVersion)
+			if err != nil {
+				t.Fatalf("pendingVersions() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("pendingVersions() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRollingUpgraderPendingVersionsMissingDir(t *testing.T) {
+	r := &RollingUpgrader{schemaDir: filepath.Join(t.TempDir(), "does-not-exist")}
+	if _, err := r.pendingVersions("0.1"); err == nil {
+		t.Fatal("expected an error for a missing schema directory")
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cassandra
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gocql/gocql"
+)
+
+// ErrGetSchemaVersion is returned when a keyspace's schema_version table has
+// no rows, e.g. before any schema has ever been applied. Callers can treat
+// this distinctly from a transport/query error.
+var ErrGetSchemaVersion = errors.New("schema_version table is empty")
+
+// scanCloser is the subset of *gocql.Iter that Iter needs. Narrowing to an
+// interface here, rather than holding *gocql.Iter directly, lets Scan be
+// exercised with a fake instead of a live query.
+type scanCloser interface {
+	Scan(dest ...interface{}) bool
+	Close() error
+}
+
+// Iter wraps gocql.Iter so Scan reports "no rows" and "transport error" as
+// distinct outcomes instead of conflating them, and always closes the
+// underlying iterator so callers can't forget to.
+type Iter struct {
+	iter scanCloser
+}
+
+// NewIter wraps iter.
+func NewIter(iter scanCloser) *Iter {
+	return &Iter{iter: iter}
+}
+
+// Scan reads the next row into dest and closes the underlying iterator. The
+// returned bool reports whether a row was read and is independent of the
+// returned error, which instead reports whether closing the iterator
+// surfaced a transport error: (true, nil) is a row with a clean close,
+// (true, err) is a row whose close failed, (false, nil) means there were no
+// more rows, and (false, err) means there were no more rows and the close
+// itself failed.
+func (i *Iter) Scan(dest ...interface{}) (bool, error) {
+	if i.iter.Scan(dest...) {
+		return true, i.iter.Close()
+	}
+	if err := i.iter.Close(); err != nil {
+		return false, err
+	}
+	return false, nil
+}
+
+// iterQuerier is the narrow slice of *gocql.Session that SchemaVersionReader
+// needs: running a CQL query and getting back an Iter. Defined as an
+// interface, rather than holding *gocql.Session directly, so
+// ReadSchemaVersion can be tested with a fake instead of a live cluster.
+type iterQuerier interface {
+	queryIter(stmt string, values ...interface{}) *Iter
+}
+
+// sessionQuerier adapts *gocql.Session to iterQuerier.
+type sessionQuerier struct {
+	session *gocql.Session
+}
+
+func (s sessionQuerier) queryIter(stmt string, values ...interface{}) *Iter {
+	return NewIter(s.session.Query(stmt, values...).Iter())
+}
+
+// SchemaVersionReader reads the schema_version row recorded for a keyspace
+// over session.
+type SchemaVersionReader struct {
+	querier iterQuerier
+}
+
+// NewSchemaVersionReader creates a SchemaVersionReader bound to session.
+func NewSchemaVersionReader(session *gocql.Session) *SchemaVersionReader {
+	return &SchemaVersionReader{querier: sessionQuerier{session: session}}
+}
+
+// ReadSchemaVersion returns the curr_version recorded in keyspace's
+// schema_version table. It returns ErrGetSchemaVersion if the table is empty
+// -- e.g. during first-time setup, where it can be handled as a warning
+// rather than a fatal error -- or a wrapped error if the query itself fails.
+func (r *SchemaVersionReader) ReadSchemaVersion(keyspace string) (string, error) {
+	iter := r.querier.queryIter(
+		fmt.Sprintf(`SELECT curr_version FROM %s.schema_version WHERE keyspace_name = ?`, keyspace),
+		keyspace,
+	)
+
+	var version string
+	ok, err := iter.Scan(&version)
+	if err != nil {
+		return "", fmt.Errorf("unable to read schema_version for keyspace %q: %w", keyspace, err)
+	}
+	if !ok {
+		return "", ErrGetSchemaVersion
+	}
+	return version, nil
+}
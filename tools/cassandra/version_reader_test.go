@@ -0,0 +1,133 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cassandra
+
+import (
+	"errors"
+	"testing"
+)
+
+type fakeScanCloser struct {
+	rows     [][]interface{}
+	next     int
+	closeErr error
+}
+
+func (f *fakeScanCloser) Scan(dest ...interface{}) bool {
+	if f.next >= len(f.rows) {
+		return false
+	}
+	row := f.rows[f.next]
+	f.next++
+	for i, d := range dest {
+		switch ptr := d.(type) {
+		case *string:
+			*ptr = row[i].(string)
+		}
+	}
+	return true
+}
+
+func (f *fakeScanCloser) Close() error {
+	return f.closeErr
+}
+
+func TestIterScan(t *testing.T) {
+	t.Run("returns true on a row", func(t *testing.T) {
+		iter := NewIter(&fakeScanCloser{rows: [][]interface{}{{"0.32"}}})
+		var version string
+		ok, err := iter.Scan(&version)
+		if err != nil || !ok {
+			t.Fatalf("Scan() = (%v, %v), want (true, nil)", ok, err)
+		}
+		if version != "0.32" {
+			t.Errorf("version = %q, want %q", version, "0.32")
+		}
+	})
+
+	t.Run("returns false, nil on no rows", func(t *testing.T) {
+		iter := NewIter(&fakeScanCloser{})
+		var version string
+		ok, err := iter.Scan(&version)
+		if err != nil || ok {
+			t.Fatalf("Scan() = (%v, %v), want (false, nil)", ok, err)
+		}
+	})
+
+	t.Run("surfaces a close error on no rows", func(t *testing.T) {
+		iter := NewIter(&fakeScanCloser{closeErr: errors.New("transport error")})
+		var version string
+		ok, err := iter.Scan(&version)
+		if ok || err == nil {
+			t.Fatalf("Scan() = (%v, %v), want (false, err)", ok, err)
+		}
+	})
+
+	t.Run("surfaces a close error after a row", func(t *testing.T) {
+		iter := NewIter(&fakeScanCloser{rows: [][]interface{}{{"0.32"}}, closeErr: errors.New("transport error")})
+		var version string
+		ok, err := iter.Scan(&version)
+		if !ok || err == nil {
+			t.Fatalf("Scan() = (%v, %v), want (true, err)", ok, err)
+		}
+	})
+}
+
+type fakeIterQuerier struct {
+	iter *Iter
+}
+
+func (f *fakeIterQuerier) queryIter(stmt string, values ...interface{}) *Iter {
+	return f.iter
+}
+
+func TestReadSchemaVersion(t *testing.T) {
+	t.Run("returns the recorded version", func(t *testing.T) {
+		reader := &SchemaVersionReader{querier: &fakeIterQuerier{
+			iter: NewIter(&fakeScanCloser{rows: [][]interface{}{{"0.32"}}}),
+		}}
+		version, err := reader.ReadSchemaVersion("ks")
+		if err != nil {
+			t.Fatalf("ReadSchemaVersion() error = %v", err)
+		}
+		if version != "0.32" {
+			t.Errorf("version = %q, want %q", version, "0.32")
+		}
+	})
+
+	t.Run("returns ErrGetSchemaVersion when the table is empty", func(t *testing.T) {
+		reader := &SchemaVersionReader{querier: &fakeIterQuerier{iter: NewIter(&fakeScanCloser{})}}
+		_, err := reader.ReadSchemaVersion("ks")
+		if !errors.Is(err, ErrGetSchemaVersion) {
+			t.Errorf("err = %v, want ErrGetSchemaVersion", err)
+		}
+	})
+
+	t.Run("wraps a transport error", func(t *testing.T) {
+		reader := &SchemaVersionReader{querier: &fakeIterQuerier{
+			iter: NewIter(&fakeScanCloser{closeErr: errors.New("connection reset")}),
+		}}
+		_, err := reader.ReadSchemaVersion("ks")
+		if err == nil || errors.Is(err, ErrGetSchemaVersion) {
+			t.Errorf("err = %v, want a wrapped transport error", err)
+		}
+	})
+}
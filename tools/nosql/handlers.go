@@ -0,0 +1,110 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package nosql
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/urfave/cli"
+
+	"github.com/uber/cadence/tools/common/schema"
+)
+
+// SetupSchema runs the setup-schema CLI handler against the plugin
+// registered under pluginName, the driver-agnostic replacement for each
+// per-database package's own setupSchema handler. It opens a single DB
+// connection for the whole operation, since Setup issues many statements
+// and each plugin's schema.DB is expected to serve them all over one
+// connection rather than reconnecting per statement.
+func SetupSchema(pluginName string, cfg PluginConfig, cli *cli.Context) error {
+	plugin, err := GetPlugin(pluginName)
+	if err != nil {
+		return err
+	}
+	db, err := plugin.NewDB(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return schema.Setup(cli, db)
+}
+
+// UpdateSchema runs the update-schema CLI handler against the plugin
+// registered under pluginName, the driver-agnostic replacement for each
+// per-database package's own updateSchema handler. See SetupSchema for why
+// it runs over a single connection.
+func UpdateSchema(pluginName string, cfg PluginConfig, cli *cli.Context) error {
+	plugin, err := GetPlugin(pluginName)
+	if err != nil {
+		return err
+	}
+	db, err := plugin.NewDB(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return schema.Update(cli, db)
+}
+
+// CreateKeyspace creates a keyspace/database named name against the plugin
+// registered under pluginName, using datacenter-aware replication when
+// datacenter is non-empty. This is the driver-agnostic replacement for
+// per-database createKeyspace CLI handlers.
+func CreateKeyspace(pluginName string, cfg PluginConfig, name string, datacenter string) error {
+	plugin, err := GetPlugin(pluginName)
+	if err != nil {
+		return err
+	}
+	defer plugin.Close()
+	if name == "" {
+		return fmt.Errorf("missing keyspace name")
+	}
+	return plugin.CreateKeyspace(cfg, name, datacenter)
+}
+
+// VerifyCompatibleVersion checks that keyspace's recorded schema version,
+// read through the plugin registered under pluginName, is greater than or
+// equal to expectedVersion.
+func VerifyCompatibleVersion(pluginName string, cfg PluginConfig, keyspace string, expectedVersion string) error {
+	plugin, err := GetPlugin(pluginName)
+	if err != nil {
+		return err
+	}
+	defer plugin.Close()
+
+	version, err := plugin.ReadSchemaVersion(cfg, keyspace)
+	if errors.Is(err, ErrSchemaVersionNotFound) {
+		log.Printf("keyspace %q has no schema_version rows yet, skipping version check", keyspace)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("unable to read schema version for keyspace %q: %v", keyspace, err)
+	}
+	if VersionLess(version, expectedVersion) {
+		return fmt.Errorf(
+			"version mismatch for keyspace %q: expected %v but found %v",
+			keyspace, expectedVersion, version,
+		)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package nosql
+
+import (
+	"testing"
+
+	"github.com/uber/cadence/tools/common/schema"
+)
+
+type fakePlugin struct {
+	version    string
+	versionErr error
+	closed     bool
+}
+
+func (p *fakePlugin) CreateKeyspace(cfg PluginConfig, name string, datacenter string) error {
+	return nil
+}
+
+func (p *fakePlugin) ReadSchemaVersion(cfg PluginConfig, keyspace string) (string, error) {
+	return p.version, p.versionErr
+}
+
+func (p *fakePlugin) NewDB(cfg PluginConfig) (schema.DB, error) {
+	return nil, nil
+}
+
+func (p *fakePlugin) Close() {
+	p.closed = true
+}
+
+func TestVerifyCompatibleVersion(t *testing.T) {
+	tests := []struct {
+		name     string
+		version  string
+		expected string
+		wantErr  bool
+	}{
+		{name: "matches", version: "0.32", expected: "0.32", wantErr: false},
+		{name: "ahead", version: "0.33", expected: "0.32", wantErr: false},
+		{name: "behind", version: "0.9", expected: "0.10", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			RegisterPlugin("fake", &fakePlugin{version: tt.version})
+			err := VerifyCompatibleVersion("fake", PluginConfig{}, "ks", tt.expected)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("VerifyCompatibleVersion() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifyCompatibleVersionClosesPlugin(t *testing.T) {
+	plugin := &fakePlugin{version: "0.32"}
+	RegisterPlugin("fake-close", plugin)
+	if err := VerifyCompatibleVersion("fake-close", PluginConfig{}, "ks", "0.32"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !plugin.closed {
+		t.Errorf("expected plugin to be closed after VerifyCompatibleVersion")
+	}
+}
+
+func TestVerifyCompatibleVersionUnknownPlugin(t *testing.T) {
+	if err := VerifyCompatibleVersion("does-not-exist", PluginConfig{}, "ks", "0.32"); err == nil {
+		t.Fatalf("expected error for unknown plugin")
+	}
+}
+
+func TestVerifyCompatibleVersionNoSchemaVersionRowsYet(t *testing.T) {
+	RegisterPlugin("fake-no-rows", &fakePlugin{versionErr: ErrSchemaVersionNotFound})
+	if err := VerifyCompatibleVersion("fake-no-rows", PluginConfig{}, "ks", "0.32"); err != nil {
+		t.Errorf("expected first-time setup (no schema_version rows) to be skipped, got: %v", err)
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package nosql provides a driver-agnostic layer over per-database schema
+// tooling (today: tools/cassandra). Each NoSQL plugin registers itself under
+// its config.NoSQL.PluginName, so schema management CLI handlers and version
+// checks can dispatch by name instead of hardcoding a single database.
+package nosql
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/uber/cadence/common/config"
+	"github.com/uber/cadence/tools/common/schema"
+)
+
+// ErrSchemaVersionNotFound is the sentinel a NoSQLPlugin.ReadSchemaVersion
+// implementation should return when the target keyspace has no schema_version
+// rows yet, e.g. before any schema has ever been applied. VerifyCompatibleVersion
+// treats it as a skip-with-warning rather than a fatal error, the same way
+// tools/cassandra's direct-connection CheckCompatibleVersion treats its own
+// ErrGetSchemaVersion; it lives here, rather than in tools/cassandra, so this
+// package can recognize it without importing a specific plugin's package.
+var ErrSchemaVersionNotFound = errors.New("keyspace has no schema_version rows yet")
+
+// PluginConfig is the connection information a NoSQLPlugin needs to reach a
+// specific cluster: hosts/port/credentials/TLS, plus the keyspace the call
+// applies to. It mirrors tools/cassandra's CQLClientConfig so that package
+// can convert one-to-one, and gives a future Mongo/Scylla/Dynamo plugin the
+// same shape to translate into its own driver config.
+type PluginConfig struct {
+	Hosts                 string
+	Port                  int
+	User                  string
+	Password              string
+	Keyspace              string
+	AllowedAuthenticators []string
+	Timeout               int
+	ConnectTimeout        int
+	ProtoVersion          int
+	NumReplicas           int
+	TLS                   *config.TLS
+}
+
+// NoSQLPlugin is the set of schema operations a NoSQL datastore driver must
+// provide to participate in driver-agnostic schema tooling (setup, update,
+// keyspace creation, and version verification). Every method takes the
+// PluginConfig needed to reach the target cluster, since the registry has no
+// other way to learn which hosts/credentials/TLS settings to use.
+type NoSQLPlugin interface {
+	// CreateKeyspace creates a keyspace/database named name against the
+	// cluster described by cfg. If datacenter is non-empty the implementation
+	// should use a topology-aware replication strategy scoped to that
+	// datacenter.
+	CreateKeyspace(cfg PluginConfig, name string, datacenter string) error
+	// ReadSchemaVersion returns the current schema version recorded for
+	// keyspace on the cluster described by cfg.
+	ReadSchemaVersion(cfg PluginConfig, keyspace string) (string, error)
+	// NewDB opens a single connection to the cluster described by cfg and
+	// returns it as a tools/common/schema.DB. SetupSchema/UpdateSchema drive
+	// the full setup/update lifecycle -- version-table bookkeeping, dropping
+	// all tables on --overwrite, writing the update history -- through the
+	// one returned DB, rather than one short-lived connection per statement.
+	NewDB(cfg PluginConfig) (schema.DB, error)
+	// Close releases any resources held by the plugin.
+	Close()
+}
+
+var (
+	pluginsMu sync.RWMutex
+	plugins   = make(map[string]NoSQLPlugin)
+)
+
+// RegisterPlugin registers a NoSQLPlugin under name, overwriting any plugin
+// previously registered under the same name. Plugins are expected to call
+// this from an init() function.
+func RegisterPlugin(name string, plugin NoSQLPlugin) {
+	pluginsMu.Lock()
+	defer pluginsMu.Unlock()
+	plugins[name] = plugin
+}
+
+// GetPlugin returns the NoSQLPlugin registered under name, or an error if no
+// plugin has been registered under that name.
+func GetPlugin(name string) (NoSQLPlugin, error) {
+	pluginsMu.RLock()
+	defer pluginsMu.RUnlock()
+	plugin, ok := plugins[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown NoSQL plugin name: %v", name)
+	}
+	return plugin, nil
+}
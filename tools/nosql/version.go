@@ -0,0 +1,53 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package nosql
+
+import (
+	"strconv"
+	"strings"
+)
+
+// VersionLess reports whether v is an older version than min, comparing
+// dot-separated numeric components left to right (e.g. "3.11.6" < "4.0.0",
+// "0.9" < "0.10"). Plain string comparison gets this wrong the moment a
+// component reaches two digits, so every plugin and tool in this tree that
+// needs to order schema/node versions should go through this instead of
+// rolling its own comparison.
+func VersionLess(v, min string) bool {
+	if min == "" {
+		return false
+	}
+	vParts := strings.Split(v, ".")
+	minParts := strings.Split(min, ".")
+	for i := 0; i < len(vParts) || i < len(minParts); i++ {
+		var vn, mn int
+		if i < len(vParts) {
+			vn, _ = strconv.Atoi(vParts[i])
+		}
+		if i < len(minParts) {
+			mn, _ = strconv.Atoi(minParts[i])
+		}
+		if vn != mn {
+			return vn < mn
+		}
+	}
+	return false
+}
@@ -0,0 +1,44 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package nosql
+
+import "testing"
+
+func TestVersionLess(t *testing.T) {
+	tests := []struct {
+		v, min string
+		want   bool
+	}{
+		{"3.11.6", "4.0.0", true},
+		{"4.0.0", "3.11.6", false},
+		{"0.9", "0.10", true},
+		{"0.10", "0.9", false},
+		{"1.2.3", "1.2.3", false},
+		{"1.2", "1.2.0", false},
+		{"1.2.0", "1.2", false},
+		{"1.2.3", "", false},
+	}
+	for _, tt := range tests {
+		if got := VersionLess(tt.v, tt.min); got != tt.want {
+			t.Errorf("VersionLess(%q, %q) = %v, want %v", tt.v, tt.min, got, tt.want)
+		}
+	}
+}